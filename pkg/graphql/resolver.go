@@ -0,0 +1,224 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/vulcanize/vulcanizedb/pkg/ipfs"
+)
+
+// PayloadFetcher retrieves the IPLD payload for a given block number so that
+// it can be run back through the Screener. It is satisfied by whatever
+// package already knows how to load IPLDPayloads off of Postgres/IPFS; the
+// graphql package only depends on this narrow interface.
+type PayloadFetcher interface {
+	FetchPayload(blockNumber int64) (*ipfs.IPLDPayload, error)
+}
+
+// Resolver is the root GraphQL resolver; it runs queries through the same
+// ResponseScreener and StreamFilters/ResponsePayload types as the stream API,
+// but only exposes a subset of their filters today: transactions is Src/Dst
+// only (no MethodSigs/CreationsOnly/MinValue/MaxValue), receipts is topic0
+// only (no full positional Topics, Addresses, or LogsOnly), stateNodes and
+// storageNodes take Addresses/IncludeIntermediate but no KeyPrefix or
+// WithProof, and there is no way to request the dag-cbor Codec/IPLDs output.
+// Extend the arg structs and resolver methods below as those are needed over
+// this transport.
+//
+// This gap has only grown since the resolver was first written: every
+// StreamFilters addition landed on the stream API alone (MethodSigs,
+// CreationsOnly, MinValue/MaxValue, full Topics/Addresses/LogsOnly,
+// KeyPrefix, WithProof, Codec) without a matching pkg/graphql change, so the
+// two transports are no longer consistent despite sharing the underlying
+// types. Bringing GraphQL back in sync with the stream API's filter surface
+// is its own follow-up item, not something to pick up incidentally the next
+// time StreamFilters grows again.
+type Resolver struct {
+	fetcher  PayloadFetcher
+	screener ipfs.ResponseScreener
+}
+
+// NewResolver creates a new Resolver
+func NewResolver(fetcher PayloadFetcher, screener ipfs.ResponseScreener) *Resolver {
+	return &Resolver{fetcher: fetcher, screener: screener}
+}
+
+// EthBlockArgs are the arguments to the ethBlock root query
+type EthBlockArgs struct {
+	Number int32
+}
+
+// EthBlock resolves the query's ethBlock field
+func (r *Resolver) EthBlock(ctx context.Context, args EthBlockArgs) (*ethBlockResolver, error) {
+	payload, err := r.fetcher.FetchPayload(int64(args.Number))
+	if err != nil {
+		return nil, fmt.Errorf("graphql: failed to fetch payload for block %d: %w", args.Number, err)
+	}
+	if payload == nil {
+		return nil, nil
+	}
+	return &ethBlockResolver{payload: payload, screener: r.screener}, nil
+}
+
+// ethBlockResolver resolves the fields on the EthBlock type by running the
+// requested filters through the Screener on demand
+type ethBlockResolver struct {
+	payload  *ipfs.IPLDPayload
+	screener ipfs.ResponseScreener
+}
+
+func (b *ethBlockResolver) Header() hexutil.Bytes {
+	return b.payload.HeaderRLP
+}
+
+func (b *ethBlockResolver) Uncles() ([]hexutil.Bytes, error) {
+	response, err := b.screener.ScreenResponse(&ipfs.StreamFilters{
+		HeaderFilter: ipfs.HeaderFilter{Uncles: true},
+	}, *b.payload)
+	if err != nil {
+		return nil, err
+	}
+	return toBytesSlice(response.UnclesRlp), nil
+}
+
+// TransactionArgs are the arguments to the transactions field
+type TransactionArgs struct {
+	Src *[]string
+	Dst *[]string
+}
+
+func (b *ethBlockResolver) Transactions(args TransactionArgs) ([]*rlpResolver, error) {
+	response, err := b.screener.ScreenResponse(&ipfs.StreamFilters{
+		TrxFilter: ipfs.TrxFilter{Src: stringSlice(args.Src), Dst: stringSlice(args.Dst)},
+	}, *b.payload)
+	if err != nil {
+		return nil, err
+	}
+	return toRLPResolvers(response.TransactionsRlp), nil
+}
+
+// ReceiptArgs are the arguments to the receipts field
+type ReceiptArgs struct {
+	Topic0s *[]string
+}
+
+func (b *ethBlockResolver) Receipts(args ReceiptArgs) ([]*rlpResolver, error) {
+	receiptFilter := ipfs.ReceiptFilter{}
+	receiptFilter.Topics[0] = stringSlice(args.Topic0s)
+	response, err := b.screener.ScreenResponse(&ipfs.StreamFilters{
+		ReceiptFilter: receiptFilter,
+	}, *b.payload)
+	if err != nil {
+		return nil, err
+	}
+	return toRLPResolvers(response.ReceiptsRlp), nil
+}
+
+// StateNodeArgs are the arguments to the stateNodes field
+type StateNodeArgs struct {
+	Addresses           *[]string
+	IncludeIntermediate *bool
+}
+
+func (b *ethBlockResolver) StateNodes(args StateNodeArgs) ([]*stateNodeResolver, error) {
+	includeIntermediate := args.IncludeIntermediate != nil && *args.IncludeIntermediate
+	response, err := b.screener.ScreenResponse(&ipfs.StreamFilters{
+		StateFilter: ipfs.StateFilter{Addresses: stringSlice(args.Addresses), IntermediateNodes: includeIntermediate},
+	}, *b.payload)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*stateNodeResolver, 0, len(response.StateNodesRlp))
+	for key, values := range response.StateNodesRlp {
+		for _, value := range values {
+			resolvers = append(resolvers, &stateNodeResolver{key: key.Bytes(), rlp: value})
+		}
+	}
+	return resolvers, nil
+}
+
+// StorageNodeArgs are the arguments to the storageNodes field
+type StorageNodeArgs struct {
+	Addresses *[]string
+	Slots     *[]string
+}
+
+func (b *ethBlockResolver) StorageNodes(args StorageNodeArgs) ([]*storageNodeResolver, error) {
+	response, err := b.screener.ScreenResponse(&ipfs.StreamFilters{
+		StorageFilter: ipfs.StorageFilter{Addresses: stringSlice(args.Addresses), StorageKeys: stringSlice(args.Slots)},
+	}, *b.payload)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*storageNodeResolver, 0)
+	for stateKey, storageNodes := range response.StorageNodesRlp {
+		for storageKey, values := range storageNodes {
+			for _, value := range values {
+				resolvers = append(resolvers, &storageNodeResolver{stateKey: stateKey.Bytes(), storageKey: storageKey.Bytes(), rlp: value})
+			}
+		}
+	}
+	return resolvers, nil
+}
+
+type rlpResolver struct{ rlp []byte }
+
+func (r *rlpResolver) Rlp() hexutil.Bytes { return r.rlp }
+
+type stateNodeResolver struct {
+	key []byte
+	rlp []byte
+}
+
+func (r *stateNodeResolver) Key() hexutil.Bytes { return r.key }
+func (r *stateNodeResolver) Rlp() hexutil.Bytes { return r.rlp }
+
+type storageNodeResolver struct {
+	stateKey   []byte
+	storageKey []byte
+	rlp        []byte
+}
+
+func (r *storageNodeResolver) StateKey() hexutil.Bytes   { return r.stateKey }
+func (r *storageNodeResolver) StorageKey() hexutil.Bytes { return r.storageKey }
+func (r *storageNodeResolver) Rlp() hexutil.Bytes        { return r.rlp }
+
+func toBytesSlice(rlps [][]byte) []hexutil.Bytes {
+	out := make([]hexutil.Bytes, len(rlps))
+	for i, b := range rlps {
+		out[i] = b
+	}
+	return out
+}
+
+func toRLPResolvers(rlps [][]byte) []*rlpResolver {
+	out := make([]*rlpResolver, len(rlps))
+	for i, b := range rlps {
+		out[i] = &rlpResolver{rlp: b}
+	}
+	return out
+}
+
+func stringSlice(s *[]string) []string {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
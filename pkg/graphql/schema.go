@@ -0,0 +1,68 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+// schema is the GraphQL schema exposed over HTTP (there is no WebSocket
+// transport, see Server), covering a subset of the filter surface of
+// ipfs.StreamFilters/ipfs.ResponsePayload (see Resolver for which filters are
+// not exposed) so that the same underlying Screener can serve both the stream
+// API and ad-hoc queries
+const schema = `
+  schema {
+    query: Query
+  }
+
+  # Query is the root query type
+  type Query {
+    ethBlock(number: Int!): EthBlock
+  }
+
+  # EthBlock exposes the filterable slices of a single block's IPLD payload.
+  # Every field is non-null: the resolvers return plain Go values/slices
+  # rather than pointers, so nothing here can come back as GraphQL null.
+  type EthBlock {
+    header: Bytes!
+    uncles: [Bytes!]!
+    transactions(src: [String!], dst: [String!]): [Transaction!]!
+    receipts(topic0s: [String!]): [Receipt!]!
+    stateNodes(addresses: [String!], includeIntermediate: Boolean): [StateNode!]!
+    storageNodes(addresses: [String!], slots: [String!]): [StorageNode!]!
+  }
+
+  type Transaction {
+    rlp: Bytes!
+  }
+
+  type Receipt {
+    rlp: Bytes!
+  }
+
+  type StateNode {
+    key: Bytes!
+    rlp: Bytes!
+  }
+
+  type StorageNode {
+    stateKey: Bytes!
+    storageKey: Bytes!
+    rlp: Bytes!
+  }
+
+  # Bytes is a hex-encoded (0x-prefixed) arbitrary length byte array, matching
+  # go-ethereum's graphql Bytes scalar
+  scalar Bytes
+`
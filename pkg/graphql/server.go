@@ -0,0 +1,56 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// Config holds the settings needed to stand up the GraphQL endpoint.
+// Endpoint is meant to be sourced from a top-level "graphqlEndpoint" config
+// option (e.g. graphqlEndpoint = "127.0.0.1:8083" in the environment toml),
+// but this tree has no cmd/config package to read such a toml and populate
+// it, so only an in-process caller can set it for now.
+type Config struct {
+	Endpoint string
+}
+
+// Server wraps the graphql-go relay HTTP handler for the ethBlock schema; it
+// serves queries over plain HTTP only and has no WebSocket transport
+type Server struct {
+	config  Config
+	handler http.Handler
+}
+
+// NewServer builds a Server that serves the schema using resolver to answer
+// queries; it panics on an invalid schema since that indicates a programmer
+// error rather than a runtime condition
+func NewServer(config Config, resolver *Resolver) *Server {
+	parsedSchema := graphql.MustParseSchema(schema, resolver)
+	return &Server{
+		config:  config,
+		handler: &relay.Handler{Schema: parsedSchema},
+	}
+}
+
+// ListenAndServe starts serving the GraphQL endpoint at the configured address
+func (s *Server) ListenAndServe() error {
+	return http.ListenAndServe(s.config.Endpoint, s.handler)
+}
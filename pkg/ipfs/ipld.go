@@ -0,0 +1,147 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	cid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// Output codecs a subscriber can request via StreamFilters.Codec
+const (
+	// CodecRLP is the default: every filter path appends its raw RLP bytes
+	CodecRLP = "rlp"
+	// CodecDAGCBOR replaces the raw RLP slices with IPLD blocks, keyed by
+	// cid.Cid, that a subscriber can push directly into an IPFS/IPLD
+	// blockstore and traverse with go-ipld-prime selectors
+	CodecDAGCBOR = "dag-cbor"
+)
+
+// eth-* IPLD multicodecs, see https://github.com/multiformats/multicodec
+const (
+	MEthBlock           = 0x90
+	MEthBlockList       = 0x91
+	MEthTxTrie          = 0x92
+	MEthTx              = 0x93
+	MEthTxReceiptTrie   = 0x94
+	MEthTxReceipt       = 0x95
+	MEthStateTrie       = 0x96
+	MEthAccountSnapshot = 0x97
+	MEthStorageTrie     = 0x98
+	MEthReceiptLogTrie  = 0x99
+	MEthReceiptLog      = 0x9a
+)
+
+// rawToCid derives the CID for an eth IPLD block the same way go-ipld-eth
+// does: a keccak256 multihash of the block's raw (RLP) bytes tagged with the
+// multicodec for what kind of eth object those bytes decode to
+func rawToCid(codec uint64, raw []byte) (cid.Cid, error) {
+	hash, err := mh.Sum(raw, mh.KECCAK_256, -1)
+	if err != nil {
+		return cid.Cid{}, err
+	}
+	return cid.NewCidV1(codec, hash), nil
+}
+
+// addIPLD derives the CID for raw under codec and stores the (CID, raw) pair
+// into response.IPLDs, lazily initializing the map on first use
+func addIPLD(response *ResponsePayload, codec uint64, raw []byte) error {
+	id, err := rawToCid(codec, raw)
+	if err != nil {
+		return err
+	}
+	if response.IPLDs == nil {
+		response.IPLDs = make(map[cid.Cid][]byte)
+	}
+	response.IPLDs[id] = raw
+	return nil
+}
+
+// convertToIPLDs replaces every raw RLP slice on response with an equivalent
+// entry in response.IPLDs, tagged with the appropriate eth-* multicodec, and
+// clears the RLP slices so the two representations aren't shipped redundantly
+func convertToIPLDs(response *ResponsePayload) error {
+	for _, raw := range response.HeadersRlp {
+		if err := addIPLD(response, MEthBlock, raw); err != nil {
+			return err
+		}
+	}
+	for _, raw := range response.UnclesRlp {
+		if err := addIPLD(response, MEthBlock, raw); err != nil {
+			return err
+		}
+	}
+	for _, raw := range response.TransactionsRlp {
+		if err := addIPLD(response, MEthTx, raw); err != nil {
+			return err
+		}
+	}
+	for _, raw := range response.ReceiptsRlp {
+		if err := addIPLD(response, MEthTxReceipt, raw); err != nil {
+			return err
+		}
+	}
+	for _, logs := range response.LogsRlp {
+		for _, logRlp := range logs {
+			if err := addIPLD(response, MEthReceiptLog, logRlp); err != nil {
+				return err
+			}
+		}
+	}
+	for _, nodes := range response.StateNodesRlp {
+		for _, raw := range nodes {
+			if err := addIPLD(response, MEthStateTrie, raw); err != nil {
+				return err
+			}
+		}
+	}
+	for _, storageNodes := range response.StorageNodesRlp {
+		for _, raws := range storageNodes {
+			for _, raw := range raws {
+				if err := addIPLD(response, MEthStorageTrie, raw); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, proof := range response.StateProofs {
+		for _, raw := range proof {
+			if err := addIPLD(response, MEthStateTrie, raw); err != nil {
+				return err
+			}
+		}
+	}
+	for _, storageProofs := range response.StorageProofs {
+		for _, proof := range storageProofs {
+			for _, raw := range proof {
+				if err := addIPLD(response, MEthStorageTrie, raw); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	response.HeadersRlp = nil
+	response.UnclesRlp = nil
+	response.TransactionsRlp = nil
+	response.ReceiptsRlp = nil
+	response.LogsRlp = nil
+	response.StateNodesRlp = nil
+	response.StorageNodesRlp = nil
+	response.StateProofs = nil
+	response.StorageProofs = nil
+	return nil
+}
@@ -0,0 +1,97 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRawToCidDeterministic(t *testing.T) {
+	raw := []byte("some rlp bytes")
+	first, err := rawToCid(MEthBlock, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := rawToCid(MEthBlock, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("expected the same raw bytes and codec to derive the same CID")
+	}
+	other, err := rawToCid(MEthTx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == other {
+		t.Error("expected different codecs to derive different CIDs for the same bytes")
+	}
+}
+
+func TestConvertToIPLDsTagsLogsWithTheirOwnCodec(t *testing.T) {
+	key := common.BytesToHash([]byte("tx"))
+	logRlp := []byte("log rlp")
+	response := &ResponsePayload{
+		LogsRlp: map[common.Hash][][]byte{key: {logRlp}},
+	}
+	if err := convertToIPLDs(response); err != nil {
+		t.Fatal(err)
+	}
+	wantCid, err := rawToCid(MEthReceiptLog, logRlp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := response.IPLDs[wantCid]; !ok {
+		t.Error("expected the log to be tagged with MEthReceiptLog, not MEthTxReceipt")
+	}
+	if response.LogsRlp != nil {
+		t.Error("expected LogsRlp to be cleared after conversion")
+	}
+}
+
+func TestConvertToIPLDsConvertsProofs(t *testing.T) {
+	stateKey := common.BytesToHash([]byte("account"))
+	storageKey := common.BytesToHash([]byte("slot"))
+	stateRlp := []byte("state proof node")
+	storageRlp := []byte("storage proof node")
+	response := &ResponsePayload{
+		StateProofs:   map[common.Hash][][]byte{stateKey: {stateRlp}},
+		StorageProofs: map[common.Hash]map[common.Hash][][]byte{stateKey: {storageKey: {storageRlp}}},
+	}
+	if err := convertToIPLDs(response); err != nil {
+		t.Fatal(err)
+	}
+	stateCid, err := rawToCid(MEthStateTrie, stateRlp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := response.IPLDs[stateCid]; !ok {
+		t.Error("expected the state proof node to be converted to an IPLD")
+	}
+	storageCid, err := rawToCid(MEthStorageTrie, storageRlp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := response.IPLDs[storageCid]; !ok {
+		t.Error("expected the storage proof node to be converted to an IPLD")
+	}
+	if response.StateProofs != nil || response.StorageProofs != nil {
+		t.Error("expected StateProofs/StorageProofs to be cleared after conversion")
+	}
+}
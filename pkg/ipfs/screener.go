@@ -20,6 +20,7 @@ import (
 	"bytes"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -59,6 +60,11 @@ func (s *Screener) ScreenResponse(streamFilters *StreamFilters, payload IPLDPayl
 	if err != nil {
 		return nil, err
 	}
+	if streamFilters.Codec == CodecDAGCBOR {
+		if err := convertToIPLDs(response); err != nil {
+			return nil, err
+		}
+	}
 	return response, nil
 }
 
@@ -88,7 +94,7 @@ func checkRange(start, end, actual int64) bool {
 func (s *Screener) filterTransactions(streamFilters *StreamFilters, response *ResponsePayload, payload IPLDPayload) error {
 	if !streamFilters.TrxFilter.Off && checkRange(streamFilters.TrxFilter.StartingBlock, streamFilters.TrxFilter.EndingBlock, payload.BlockNumber.Int64()) {
 		for i, trx := range payload.BlockBody.Transactions {
-			if checkTransactions(streamFilters.TrxFilter.Src, streamFilters.TrxFilter.Dst, payload.TrxMetaData[i].Src, payload.TrxMetaData[i].Dst) {
+			if checkTransactions(streamFilters.TrxFilter, payload.TrxMetaData[i].Src, payload.TrxMetaData[i].Dst, trx) {
 				trxBuffer := new(bytes.Buffer)
 				err := trx.EncodeRLP(trxBuffer)
 				if err != nil {
@@ -101,7 +107,28 @@ func (s *Screener) filterTransactions(streamFilters *StreamFilters, response *Re
 	return nil
 }
 
-func checkTransactions(wantedSrc, wantedDst []string, actualSrc, actualDst string) bool {
+// checkTransactions ANDs together every predicate set on filter: address
+// (Src/Dst), CreationsOnly, MethodSigs, and the MinValue/MaxValue wei range
+func checkTransactions(filter TrxFilter, actualSrc, actualDst string, trx *types.Transaction) bool {
+	if !trxAddressMatches(filter.Src, filter.Dst, actualSrc, actualDst) {
+		return false
+	}
+	if filter.CreationsOnly && trx.To() != nil {
+		return false
+	}
+	if len(filter.MethodSigs) > 0 && !methodSigMatches(filter.MethodSigs, trx.Data()) {
+		return false
+	}
+	if filter.MinValue != nil && trx.Value().Cmp(filter.MinValue) < 0 {
+		return false
+	}
+	if filter.MaxValue != nil && trx.Value().Cmp(filter.MaxValue) > 0 {
+		return false
+	}
+	return true
+}
+
+func trxAddressMatches(wantedSrc, wantedDst []string, actualSrc, actualDst string) bool {
 	// If we aren't filtering for any addresses, every transaction is a go
 	if len(wantedDst) == 0 && len(wantedSrc) == 0 {
 		return true
@@ -119,59 +146,252 @@ func checkTransactions(wantedSrc, wantedDst []string, actualSrc, actualDst strin
 	return false
 }
 
+// methodSigMatches reports whether input's leading 4-byte function selector
+// is one of wantedSigs
+func methodSigMatches(wantedSigs [][4]byte, input []byte) bool {
+	if len(input) < 4 {
+		return false
+	}
+	var sig [4]byte
+	copy(sig[:], input[:4])
+	for _, wanted := range wantedSigs {
+		if wanted == sig {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Screener) filerReceipts(streamFilters *StreamFilters, response *ResponsePayload, payload IPLDPayload) error {
-	if !streamFilters.ReceiptFilter.Off && checkRange(streamFilters.ReceiptFilter.StartingBlock, streamFilters.ReceiptFilter.EndingBlock, payload.BlockNumber.Int64()) {
-		for i, receipt := range payload.Receipts {
-			if checkReceipts(streamFilters.ReceiptFilter.Topic0s, payload.ReceiptMetaData[i].Topic0s) {
-				receiptBuffer := new(bytes.Buffer)
-				err := receipt.EncodeRLP(receiptBuffer)
+	if streamFilters.ReceiptFilter.Off || !checkRange(streamFilters.ReceiptFilter.StartingBlock, streamFilters.ReceiptFilter.EndingBlock, payload.BlockNumber.Int64()) {
+		return nil
+	}
+	// Pre-hash the wanted topics and addresses once per invocation instead of
+	// re-comparing strings in the inner loop below
+	wantedTopicHashes := hashWantedTopics(streamFilters.ReceiptFilter.Topics)
+	wantedAddresses := hashWantedAddresses(streamFilters.ReceiptFilter.Addresses)
+	filtered := wantedTopicHashes != nil || wantedAddresses != nil
+	if filtered {
+		// Short-circuit the whole block using the header's logsBloom: if none
+		// of the wanted topics/addresses could possibly be present, skip
+		// every receipt
+		var header types.Header
+		if err := rlp.DecodeBytes(payload.HeaderRLP, &header); err != nil {
+			return err
+		}
+		if !bloomMatchesFilter(header.Bloom, wantedTopicHashes, wantedAddresses) {
+			return nil
+		}
+	}
+	for i, receipt := range payload.Receipts {
+		if filtered && !bloomMatchesFilter(receipt.Bloom, wantedTopicHashes, wantedAddresses) {
+			continue
+		}
+		matchedLogs := matchingLogIndexes(wantedTopicHashes, wantedAddresses, payload.ReceiptMetaData[i])
+		if filtered && len(matchedLogs) == 0 {
+			continue
+		}
+		if streamFilters.ReceiptFilter.LogsOnly {
+			if response.LogsRlp == nil {
+				response.LogsRlp = make(map[common.Hash][][]byte)
+			}
+			for _, logIndex := range matchedLogs {
+				logRlp, err := rlp.EncodeToBytes(receipt.Logs[logIndex])
 				if err != nil {
 					return err
 				}
-				response.ReceiptsRlp = append(response.ReceiptsRlp, receiptBuffer.Bytes())
+				response.LogsRlp[receipt.TxHash] = append(response.LogsRlp[receipt.TxHash], logRlp)
 			}
+			continue
+		}
+		receiptBuffer := new(bytes.Buffer)
+		if err := receipt.EncodeRLP(receiptBuffer); err != nil {
+			return err
 		}
+		response.ReceiptsRlp = append(response.ReceiptsRlp, receiptBuffer.Bytes())
 	}
 	return nil
 }
 
-func checkReceipts(wantedTopics, actualTopics []string) bool {
-	// If we aren't filtering for any topics, all topics are a go
-	if len(wantedTopics) == 0 {
+// hashWantedTopics converts the hex-string positional topic filter into
+// common.Hash once, returning nil if no positions are filtered so callers can
+// skip all bloom/comparison work entirely
+func hashWantedTopics(wantedTopics [4][]string) *[4][]common.Hash {
+	empty := true
+	hashes := [4][]common.Hash{}
+	for i, positional := range wantedTopics {
+		if len(positional) == 0 {
+			continue
+		}
+		empty = false
+		hashes[i] = make([]common.Hash, len(positional))
+		for j, topic := range positional {
+			hashes[i][j] = common.HexToHash(topic)
+		}
+	}
+	if empty {
+		return nil
+	}
+	return &hashes
+}
+
+// hashWantedAddresses converts the hex-string address filter into
+// common.Address once, returning nil if it's empty
+func hashWantedAddresses(wantedAddresses []string) []common.Address {
+	if len(wantedAddresses) == 0 {
+		return nil
+	}
+	addresses := make([]common.Address, len(wantedAddresses))
+	for i, addr := range wantedAddresses {
+		addresses[i] = common.HexToAddress(addr)
+	}
+	return addresses
+}
+
+// bloomMatchesFilter reports whether bloom could possibly contain a log
+// satisfying both wantedTopics and wantedAddresses. Bloom filters never
+// false-negative, so a predicate bloom rules out can be trusted to rule out
+// the whole receipt/block.
+func bloomMatchesFilter(bloom types.Bloom, wantedTopics *[4][]common.Hash, wantedAddresses []common.Address) bool {
+	if len(wantedAddresses) > 0 {
+		matched := false
+		for _, addr := range wantedAddresses {
+			if types.BloomLookup(bloom, addr) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if wantedTopics == nil {
 		return true
 	}
-	for _, wantedTopic := range wantedTopics {
-		for _, actualTopic := range actualTopics {
-			if wantedTopic == actualTopic {
-				return true
+	for _, positional := range wantedTopics {
+		if len(positional) == 0 {
+			continue
+		}
+		matched := false
+		for _, hash := range positional {
+			if types.BloomLookup(bloom, hash) {
+				matched = true
+				break
 			}
 		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchingLogIndexes returns the indexes, within the receipt's logs, of the
+// logs that satisfy both wantedTopics and wantedAddresses. If neither filter
+// is set, every log index is returned (the receipt is an unconditional match).
+func matchingLogIndexes(wantedTopics *[4][]common.Hash, wantedAddresses []common.Address, metaData ReceiptMetaData) []int {
+	if wantedTopics == nil && len(wantedAddresses) == 0 {
+		indexes := make([]int, len(metaData.Topics))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes
+	}
+	var matched []int
+	for i, logTopics := range metaData.Topics {
+		if len(wantedAddresses) > 0 && !addressMatches(wantedAddresses, metaData.Addresses[i]) {
+			continue
+		}
+		if wantedTopics != nil && !logMatchesTopics(wantedTopics, logTopics) {
+			continue
+		}
+		matched = append(matched, i)
+	}
+	return matched
+}
+
+func addressMatches(wantedAddresses []common.Address, actualAddress string) bool {
+	actual := common.HexToAddress(actualAddress)
+	for _, wanted := range wantedAddresses {
+		if wanted == actual {
+			return true
+		}
 	}
 	return false
 }
 
+// logMatchesTopics checks a single log's topics against the positional
+// wantedTopics filter: each non-wildcard position must match one of the
+// candidates there, and all positions are ANDed together
+func logMatchesTopics(wantedTopics *[4][]common.Hash, logTopics []string) bool {
+	for i, positional := range wantedTopics {
+		if len(positional) == 0 {
+			continue
+		}
+		if i >= len(logTopics) {
+			return false
+		}
+		actual := common.HexToHash(logTopics[i])
+		matched := false
+		for _, wanted := range positional {
+			if wanted == actual {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *Screener) filterState(streamFilters *StreamFilters, response *ResponsePayload, payload IPLDPayload) error {
-	response.StateNodesRlp = make(map[common.Hash][]byte)
-	if !streamFilters.StateFilter.Off && checkRange(streamFilters.StateFilter.StartingBlock, streamFilters.StateFilter.EndingBlock, payload.BlockNumber.Int64()) {
-		keyFilters := make([]common.Hash, 0, len(streamFilters.StateFilter.Addresses))
-		for _, addr := range streamFilters.StateFilter.Addresses {
-			keyFilter := AddressToKey(common.HexToAddress(addr))
-			keyFilters = append(keyFilters, keyFilter)
-		}
-		for key, stateNode := range payload.StateNodes {
-			if checkNodeKeys(keyFilters, key) {
-				if stateNode.Leaf || streamFilters.StateFilter.IntermediateNodes {
-					response.StateNodesRlp[key] = stateNode.Value
-				}
+	response.StateNodesRlp = make(map[common.Hash][][]byte)
+	if streamFilters.StateFilter.Off || !checkRange(streamFilters.StateFilter.StartingBlock, streamFilters.StateFilter.EndingBlock, payload.BlockNumber.Int64()) {
+		return nil
+	}
+	if streamFilters.StateFilter.WithProof {
+		response.StateProofs = make(map[common.Hash][][]byte)
+	}
+	keyFilters := make([]common.Hash, 0, len(streamFilters.StateFilter.Addresses))
+	for _, addr := range streamFilters.StateFilter.Addresses {
+		keyFilter := AddressToKey(common.HexToAddress(addr))
+		keyFilters = append(keyFilters, keyFilter)
+	}
+	for key, path := range payload.StateNodes {
+		if !checkNodeKeys(keyFilters, streamFilters.StateFilter.KeyPrefix, key) {
+			continue
+		}
+		if streamFilters.StateFilter.WithProof {
+			response.StateProofs[key] = nodeRlps(path)
+			continue
+		}
+		for _, stateNode := range path {
+			if stateNode.Leaf || streamFilters.StateFilter.IntermediateNodes {
+				response.StateNodesRlp[key] = append(response.StateNodesRlp[key], stateNode.Value)
 			}
 		}
 	}
 	return nil
 }
 
-func checkNodeKeys(wantedKeys []common.Hash, actualKey common.Hash) bool {
+// nodeRlps flattens an ordered trie node path down to its RLP values
+func nodeRlps(path []StateNode) [][]byte {
+	rlps := make([][]byte, len(path))
+	for i, node := range path {
+		rlps[i] = node.Value
+	}
+	return rlps
+}
+
+// checkNodeKeys matches actualKey against an explicit set of wantedKeys
+// (an OR) or against keyPrefix, a variable-length run of hex nibbles that
+// actualKey's leading nibbles must match; the two are ORed together so a
+// caller can mix exact keys with a nibble-prefix subset
+func checkNodeKeys(wantedKeys []common.Hash, keyPrefix []byte, actualKey common.Hash) bool {
 	// If we aren't filtering for any specific keys, all nodes are a go
-	if len(wantedKeys) == 0 {
+	if len(wantedKeys) == 0 && len(keyPrefix) == 0 {
 		return true
 	}
 	for _, key := range wantedKeys {
@@ -179,30 +399,75 @@ func checkNodeKeys(wantedKeys []common.Hash, actualKey common.Hash) bool {
 			return true
 		}
 	}
+	if len(keyPrefix) > 0 && hasNibblePrefix(actualKey, keyPrefix) {
+		return true
+	}
 	return false
 }
 
+// hasNibblePrefix reports whether key's leading hex nibbles equal prefix,
+// where each element of prefix is a single nibble value (0x0-0xf)
+func hasNibblePrefix(key common.Hash, prefix []byte) bool {
+	if len(prefix) > len(key)*2 {
+		return false
+	}
+	keyBytes := key.Bytes()
+	for i, nibble := range prefix {
+		var actual byte
+		if i%2 == 0 {
+			actual = keyBytes[i/2] >> 4
+		} else {
+			actual = keyBytes[i/2] & 0x0f
+		}
+		if actual != nibble {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *Screener) filterStorage(streamFilters *StreamFilters, response *ResponsePayload, payload IPLDPayload) error {
-	if !streamFilters.StorageFilter.Off && checkRange(streamFilters.StorageFilter.StartingBlock, streamFilters.StorageFilter.EndingBlock, payload.BlockNumber.Int64()) {
-		stateKeyFilters := make([]common.Hash, 0, len(streamFilters.StorageFilter.Addresses))
-		for _, addr := range streamFilters.StorageFilter.Addresses {
-			keyFilter := AddressToKey(common.HexToAddress(addr))
-			stateKeyFilters = append(stateKeyFilters, keyFilter)
-		}
-		storageKeyFilters := make([]common.Hash, 0, len(streamFilters.StorageFilter.StorageKeys))
-		for _, store := range streamFilters.StorageFilter.StorageKeys {
-			keyFilter := HexToKey(store)
-			storageKeyFilters = append(storageKeyFilters, keyFilter)
-		}
-		for stateKey, storageNodes := range payload.StorageNodes {
-			if checkNodeKeys(stateKeyFilters, stateKey) {
-				response.StorageNodesRlp[stateKey] = make(map[common.Hash][]byte)
-				for _, storageNode := range storageNodes {
-					if checkNodeKeys(storageKeyFilters, storageNode.Key) {
-						response.StorageNodesRlp[stateKey][storageNode.Key] = storageNode.Value
-					}
+	response.StorageNodesRlp = make(map[common.Hash]map[common.Hash][][]byte)
+	if streamFilters.StorageFilter.Off || !checkRange(streamFilters.StorageFilter.StartingBlock, streamFilters.StorageFilter.EndingBlock, payload.BlockNumber.Int64()) {
+		return nil
+	}
+	if streamFilters.StorageFilter.WithProof {
+		response.StorageProofs = make(map[common.Hash]map[common.Hash][][]byte)
+	}
+	stateKeyFilters := make([]common.Hash, 0, len(streamFilters.StorageFilter.Addresses))
+	for _, addr := range streamFilters.StorageFilter.Addresses {
+		keyFilter := AddressToKey(common.HexToAddress(addr))
+		stateKeyFilters = append(stateKeyFilters, keyFilter)
+	}
+	storageKeyFilters := make([]common.Hash, 0, len(streamFilters.StorageFilter.StorageKeys))
+	for _, store := range streamFilters.StorageFilter.StorageKeys {
+		keyFilter := HexToKey(store)
+		storageKeyFilters = append(storageKeyFilters, keyFilter)
+	}
+	for stateKey, storageNodes := range payload.StorageNodes {
+		if !checkNodeKeys(stateKeyFilters, nil, stateKey) {
+			continue
+		}
+		if streamFilters.StorageFilter.WithProof {
+			proofs := make(map[common.Hash][][]byte)
+			for _, storageNode := range storageNodes {
+				if checkNodeKeys(storageKeyFilters, streamFilters.StorageFilter.KeyPrefix, storageNode.Key) {
+					proofs[storageNode.Key] = append(proofs[storageNode.Key], storageNode.Value)
 				}
 			}
+			if len(proofs) > 0 {
+				response.StorageProofs[stateKey] = proofs
+			}
+			continue
+		}
+		response.StorageNodesRlp[stateKey] = make(map[common.Hash][][]byte)
+		for _, storageNode := range storageNodes {
+			if !checkNodeKeys(storageKeyFilters, streamFilters.StorageFilter.KeyPrefix, storageNode.Key) {
+				continue
+			}
+			if storageNode.Leaf || streamFilters.StorageFilter.IntermediateNodes {
+				response.StorageNodesRlp[stateKey][storageNode.Key] = append(response.StorageNodesRlp[stateKey][storageNode.Key], storageNode.Value)
+			}
 		}
 	}
 	return nil
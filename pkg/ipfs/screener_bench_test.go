@@ -0,0 +1,110 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// buildSyntheticPayload constructs a synthetic block with numReceipts
+// receipts, the first matchCount of which contain wantedTopic (the rest
+// carry unrelated noise topics), so callers can exercise the header-bloom
+// short-circuit, the per-receipt bloom skip, or both depending on matchCount
+func buildSyntheticPayload(numReceipts, matchCount int, wantedTopic common.Hash) (IPLDPayload, error) {
+	header := &types.Header{Number: big.NewInt(1)}
+	receipts := make(types.Receipts, numReceipts)
+	metaData := make([]ReceiptMetaData, numReceipts)
+	for i := 0; i < numReceipts; i++ {
+		topic := common.BytesToHash([]byte(fmt.Sprintf("topic-%d", i)))
+		if i < matchCount {
+			topic = wantedTopic
+		}
+		log := &types.Log{Topics: []common.Hash{topic}}
+		receipt := &types.Receipt{Logs: []*types.Log{log}}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		receipts[i] = receipt
+		metaData[i] = ReceiptMetaData{Topics: [][]string{{topic.Hex()}}}
+		header.Bloom.Add(topic.Bytes())
+	}
+	headerRLP, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return IPLDPayload{}, err
+	}
+	return IPLDPayload{
+		BlockNumber:     header.Number,
+		HeaderRLP:       headerRLP,
+		Receipts:        receipts,
+		ReceiptMetaData: metaData,
+	}, nil
+}
+
+func BenchmarkFilerReceipts(b *testing.B) {
+	wantedTopic := common.BytesToHash([]byte("wanted-topic"))
+	streamFilters := &StreamFilters{
+		ReceiptFilter: ReceiptFilter{Topics: [4][]string{{wantedTopic.Hex()}}},
+	}
+	screener := NewResponseScreener()
+
+	b.Run("header bloom short-circuit (topic absent from the whole block)", func(b *testing.B) {
+		payload, err := buildSyntheticPayload(200, 0, wantedTopic)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			response := new(ResponsePayload)
+			if err := screener.filerReceipts(streamFilters, response, payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("per-receipt bloom skip (topic present in a minority of receipts)", func(b *testing.B) {
+		payload, err := buildSyntheticPayload(200, 2, wantedTopic)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			response := new(ResponsePayload)
+			if err := screener.filerReceipts(streamFilters, response, payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("unfiltered (no wanted topics)", func(b *testing.B) {
+		payload, err := buildSyntheticPayload(200, 0, wantedTopic)
+		if err != nil {
+			b.Fatal(err)
+		}
+		unfiltered := &StreamFilters{ReceiptFilter: ReceiptFilter{}}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			response := new(ResponsePayload)
+			if err := screener.filerReceipts(unfiltered, response, payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,105 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestAddressMatches(t *testing.T) {
+	wanted := hashWantedAddresses([]string{"0x1111111111111111111111111111111111111111"})
+
+	if !addressMatches(wanted, "0x1111111111111111111111111111111111111111") {
+		t.Error("expected the wanted address to match")
+	}
+	if addressMatches(wanted, "0x2222222222222222222222222222222222222222") {
+		t.Error("expected an unrelated address not to match")
+	}
+}
+
+func TestMatchingLogIndexesAddressFilter(t *testing.T) {
+	wantedAddresses := hashWantedAddresses([]string{"0x1111111111111111111111111111111111111111"})
+	metaData := ReceiptMetaData{
+		Topics:    [][]string{{}, {}},
+		Addresses: []string{"0x1111111111111111111111111111111111111111", "0x2222222222222222222222222222222222222222"},
+	}
+
+	indexes := matchingLogIndexes(nil, wantedAddresses, metaData)
+	if len(indexes) != 1 || indexes[0] != 0 {
+		t.Errorf("expected only log index 0 to match, got %v", indexes)
+	}
+}
+
+// buildReceiptPayload builds a single-receipt synthetic block whose one log
+// was emitted by logAddr, so callers can exercise address-filtered LogsOnly
+// extraction end to end
+func buildReceiptPayload(logAddr common.Address) (IPLDPayload, error) {
+	header := &types.Header{Number: big.NewInt(1)}
+	log := &types.Log{Address: logAddr, Topics: []common.Hash{}}
+	receipt := &types.Receipt{Logs: []*types.Log{log}, TxHash: common.BytesToHash([]byte("tx"))}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	header.Bloom.Add(logAddr.Bytes())
+	headerRLP, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return IPLDPayload{}, err
+	}
+	return IPLDPayload{
+		BlockNumber: header.Number,
+		HeaderRLP:   headerRLP,
+		Receipts:    types.Receipts{receipt},
+		ReceiptMetaData: []ReceiptMetaData{
+			{Topics: [][]string{{}}, Addresses: []string{logAddr.Hex()}},
+		},
+	}, nil
+}
+
+func TestFilerReceiptsLogsOnlyAddressFiltering(t *testing.T) {
+	wantedAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	payload, err := buildReceiptPayload(wantedAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamFilters := &StreamFilters{
+		ReceiptFilter: ReceiptFilter{Addresses: []string{wantedAddr.Hex()}, LogsOnly: true},
+	}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filerReceipts(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.ReceiptsRlp) != 0 {
+		t.Fatalf("expected LogsOnly to suppress ReceiptsRlp, got %d entries", len(response.ReceiptsRlp))
+	}
+	if len(response.LogsRlp) != 1 {
+		t.Fatalf("expected exactly 1 matching tx's logs, got %d", len(response.LogsRlp))
+	}
+
+	other := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	streamFilters.ReceiptFilter.Addresses = []string{other.Hex()}
+	response = new(ResponsePayload)
+	if err := screener.filerReceipts(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.LogsRlp) != 0 {
+		t.Errorf("expected no logs to match an unrelated address, got %d", len(response.LogsRlp))
+	}
+}
@@ -0,0 +1,132 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// buildStatePayload builds a single-account state path: an intermediate node
+// followed by the account's leaf, keyed by key
+func buildStatePayload(key common.Hash) IPLDPayload {
+	return IPLDPayload{
+		BlockNumber: big.NewInt(1),
+		StateNodes: map[common.Hash][]StateNode{
+			key: {
+				{Path: []byte{0x1}, Leaf: false, Value: []byte("intermediate")},
+				{Path: []byte{0x1, 0x2}, Leaf: true, Value: []byte("leaf")},
+			},
+		},
+	}
+}
+
+func TestFilterStateIntermediateNodes(t *testing.T) {
+	key := common.BytesToHash([]byte("account"))
+	payload := buildStatePayload(key)
+	streamFilters := &StreamFilters{
+		StateFilter: StateFilter{IntermediateNodes: true},
+	}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filterState(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	nodes := response.StateNodesRlp[key]
+	if len(nodes) != 2 {
+		t.Fatalf("expected both the intermediate and leaf node RLP to be kept, got %d", len(nodes))
+	}
+	if string(nodes[0]) != "intermediate" || string(nodes[1]) != "leaf" {
+		t.Errorf("expected [intermediate, leaf] in root-to-leaf order, got %v", nodes)
+	}
+}
+
+func TestFilterStateLeafOnlyByDefault(t *testing.T) {
+	key := common.BytesToHash([]byte("account"))
+	payload := buildStatePayload(key)
+	streamFilters := &StreamFilters{StateFilter: StateFilter{}}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filterState(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	nodes := response.StateNodesRlp[key]
+	if len(nodes) != 1 || string(nodes[0]) != "leaf" {
+		t.Errorf("expected only the leaf node without IntermediateNodes, got %v", nodes)
+	}
+}
+
+func TestFilterStateWithProof(t *testing.T) {
+	key := common.BytesToHash([]byte("account"))
+	payload := buildStatePayload(key)
+	streamFilters := &StreamFilters{
+		StateFilter: StateFilter{WithProof: true},
+	}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filterState(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	proof := response.StateProofs[key]
+	if len(proof) != 2 {
+		t.Fatalf("expected the full root-to-leaf path in the proof, got %d nodes", len(proof))
+	}
+	if string(proof[0]) != "intermediate" || string(proof[1]) != "leaf" {
+		t.Errorf("expected [intermediate, leaf] in root-to-leaf order, got %v", proof)
+	}
+}
+
+// TestFilterStateWithProofMissingKeyProducesNoProof pins down a known
+// limitation rather than a desired behavior: see the disclosure on
+// IPLDPayload.StateNodes. Without a trie-walking producer this tree has no
+// way to build a root-to-divergence path for a key that was never touched in
+// the block, so an untouched address silently yields no proof at all instead
+// of the exclusion proof a client would actually want.
+func TestFilterStateWithProofMissingKeyProducesNoProof(t *testing.T) {
+	touchedKey := common.BytesToHash([]byte("account"))
+	untouchedKey := common.BytesToHash([]byte("never touched"))
+	payload := buildStatePayload(touchedKey)
+	streamFilters := &StreamFilters{
+		StateFilter: StateFilter{Addresses: nil, WithProof: true},
+	}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filterState(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := response.StateProofs[untouchedKey]; ok {
+		t.Error("expected no proof entry for a key absent from payload.StateNodes")
+	}
+}
+
+func TestHasNibblePrefix(t *testing.T) {
+	key := common.BytesToHash([]byte("account"))
+	keyBytes := key.Bytes()
+	prefix := []byte{keyBytes[0] >> 4, keyBytes[0] & 0x0f, keyBytes[1] >> 4}
+
+	if !hasNibblePrefix(key, prefix) {
+		t.Error("expected the key's own leading nibbles to match as a prefix")
+	}
+	if hasNibblePrefix(key, []byte{(keyBytes[0]>>4)+1, keyBytes[0] & 0x0f}) {
+		t.Error("expected a mismatching leading nibble not to match")
+	}
+	if hasNibblePrefix(key, make([]byte, len(keyBytes)*2+1)) {
+		t.Error("expected a too-long prefix never to match")
+	}
+}
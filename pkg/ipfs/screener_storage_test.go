@@ -0,0 +1,125 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// buildStoragePayload builds a single-slot storage path for stateKey: an
+// intermediate node followed by the slot's leaf, both keyed by storageKey
+func buildStoragePayload(stateKey, storageKey common.Hash) IPLDPayload {
+	return IPLDPayload{
+		BlockNumber: big.NewInt(1),
+		StorageNodes: map[common.Hash][]StorageNode{
+			stateKey: {
+				{Key: storageKey, Path: []byte{0x1}, Leaf: false, Value: []byte("intermediate")},
+				{Key: storageKey, Path: []byte{0x1, 0x2}, Leaf: true, Value: []byte("leaf")},
+			},
+		},
+	}
+}
+
+func TestFilterStorageIntermediateNodes(t *testing.T) {
+	stateKey := common.BytesToHash([]byte("account"))
+	storageKey := common.BytesToHash([]byte("slot"))
+	payload := buildStoragePayload(stateKey, storageKey)
+	streamFilters := &StreamFilters{
+		StorageFilter: StorageFilter{IntermediateNodes: true},
+	}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filterStorage(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	nodes := response.StorageNodesRlp[stateKey][storageKey]
+	if len(nodes) != 2 {
+		t.Fatalf("expected both the intermediate and leaf node RLP to be kept, got %d", len(nodes))
+	}
+	if string(nodes[0]) != "intermediate" || string(nodes[1]) != "leaf" {
+		t.Errorf("expected [intermediate, leaf] in root-to-leaf order, got %v", nodes)
+	}
+}
+
+func TestFilterStorageLeafOnlyByDefault(t *testing.T) {
+	stateKey := common.BytesToHash([]byte("account"))
+	storageKey := common.BytesToHash([]byte("slot"))
+	payload := buildStoragePayload(stateKey, storageKey)
+	streamFilters := &StreamFilters{StorageFilter: StorageFilter{}}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filterStorage(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	nodes := response.StorageNodesRlp[stateKey][storageKey]
+	if len(nodes) != 1 || string(nodes[0]) != "leaf" {
+		t.Errorf("expected only the leaf node without IntermediateNodes, got %v", nodes)
+	}
+}
+
+func TestFilterStorageWithProof(t *testing.T) {
+	stateKey := common.BytesToHash([]byte("account"))
+	storageKey := common.BytesToHash([]byte("slot"))
+	payload := buildStoragePayload(stateKey, storageKey)
+	streamFilters := &StreamFilters{
+		StorageFilter: StorageFilter{WithProof: true},
+	}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filterStorage(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	proof := response.StorageProofs[stateKey][storageKey]
+	if len(proof) != 2 {
+		t.Fatalf("expected the full root-to-leaf path in the proof, got %d nodes", len(proof))
+	}
+	if string(proof[0]) != "intermediate" || string(proof[1]) != "leaf" {
+		t.Errorf("expected [intermediate, leaf] in root-to-leaf order, got %v", proof)
+	}
+}
+
+func TestFilterStorageKeyPrefix(t *testing.T) {
+	stateKey := common.BytesToHash([]byte("account"))
+	storageKey := common.BytesToHash([]byte("slot"))
+	payload := buildStoragePayload(stateKey, storageKey)
+	keyBytes := storageKey.Bytes()
+	prefix := []byte{keyBytes[0] >> 4, keyBytes[0] & 0x0f}
+	streamFilters := &StreamFilters{
+		StorageFilter: StorageFilter{KeyPrefix: prefix},
+	}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filterStorage(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	nodes := response.StorageNodesRlp[stateKey][storageKey]
+	if len(nodes) != 1 || string(nodes[0]) != "leaf" {
+		t.Errorf("expected the slot matching KeyPrefix to keep its leaf, got %v", nodes)
+	}
+
+	streamFilters.StorageFilter.KeyPrefix = []byte{(keyBytes[0] >> 4) + 1}
+	response = new(ResponsePayload)
+	if err := screener.filterStorage(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := response.StorageNodesRlp[stateKey][storageKey]; len(nodes) != 0 {
+		t.Errorf("expected a mismatching KeyPrefix to exclude the slot, got %v", nodes)
+	}
+}
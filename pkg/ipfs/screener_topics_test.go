@@ -0,0 +1,106 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestLogMatchesTopics(t *testing.T) {
+	topicA := common.BytesToHash([]byte("topicA")).Hex()
+	topicB := common.BytesToHash([]byte("topicB")).Hex()
+	other := common.BytesToHash([]byte("other")).Hex()
+
+	// logMatchesTopics assumes its caller (matchingLogIndexes) has already handled
+	// the all-wildcard/nil case, so every case here has at least one
+	// non-wildcard position
+	tests := []struct {
+		name      string
+		wanted    [4][]string
+		logTopics []string
+		want      bool
+	}{
+		{"topic0 matches", [4][]string{{topicA}}, []string{topicA}, true},
+		{"topic0 mismatches", [4][]string{{topicA}}, []string{other}, false},
+		{"position 1 wildcard, position 0 must match", [4][]string{{topicA}, {}}, []string{topicA, other}, true},
+		{"AND across positions, one fails", [4][]string{{topicA}, {topicB}}, []string{topicA, other}, false},
+		{"AND across positions, both match", [4][]string{{topicA}, {topicB}}, []string{topicA, topicB}, true},
+		{"position required but log has fewer topics", [4][]string{{}, {topicB}}, []string{topicA}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashed := hashWantedTopics(tt.wanted)
+			if got := logMatchesTopics(hashed, tt.logTopics); got != tt.want {
+				t.Errorf("logMatchesTopics() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchingLogIndexesWildcard(t *testing.T) {
+	metaData := ReceiptMetaData{Topics: [][]string{{"anything"}, {"something-else"}}}
+	indexes := matchingLogIndexes(nil, nil, metaData)
+	if len(indexes) != len(metaData.Topics) {
+		t.Errorf("expected a nil topic+address filter to match every log, got indexes %v", indexes)
+	}
+}
+
+func TestHashWantedTopicsEmptyIsNil(t *testing.T) {
+	if hashWantedTopics([4][]string{}) != nil {
+		t.Error("expected nil for an all-wildcard filter")
+	}
+}
+
+func TestBloomMatchesFilterTopics(t *testing.T) {
+	present := common.BytesToHash([]byte("present"))
+	absent := common.BytesToHash([]byte("absent"))
+
+	var bloom types.Bloom
+	bloom.Add(present.Bytes())
+
+	wanted := hashWantedTopics([4][]string{{present.Hex()}})
+	if !bloomMatchesFilter(bloom, wanted, nil) {
+		t.Error("expected bloom lookup for a present topic to match")
+	}
+
+	wanted = hashWantedTopics([4][]string{{absent.Hex()}})
+	if bloomMatchesFilter(bloom, wanted, nil) {
+		t.Error("expected bloom lookup for an absent topic to not match")
+	}
+}
+
+func TestFilerReceiptsTopicFiltering(t *testing.T) {
+	wantedTopic := common.BytesToHash([]byte("wanted"))
+	payload, err := buildSyntheticPayload(5, 1, wantedTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamFilters := &StreamFilters{
+		ReceiptFilter: ReceiptFilter{Topics: [4][]string{{wantedTopic.Hex()}}},
+	}
+	response := new(ResponsePayload)
+	screener := NewResponseScreener()
+	if err := screener.filerReceipts(streamFilters, response, payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.ReceiptsRlp) != 1 {
+		t.Fatalf("expected exactly 1 matching receipt, got %d", len(response.ReceiptsRlp))
+	}
+}
@@ -0,0 +1,112 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func transferTrx(value *big.Int, data []byte) *types.Transaction {
+	return types.NewTransaction(0, common.HexToAddress("0x2222222222222222222222222222222222222222"), value, 21000, big.NewInt(1), data)
+}
+
+func TestCheckTransactionsMethodSigs(t *testing.T) {
+	transferSig := [4]byte{0xa9, 0x05, 0x9c, 0xbb}
+	trx := transferTrx(big.NewInt(0), append(transferSig[:], make([]byte, 64)...))
+
+	filter := TrxFilter{MethodSigs: [][4]byte{transferSig}}
+	if !checkTransactions(filter, "", "", trx) {
+		t.Error("expected a matching method selector to pass")
+	}
+
+	otherSig := [4]byte{0x00, 0x00, 0x00, 0x01}
+	filter = TrxFilter{MethodSigs: [][4]byte{otherSig}}
+	if checkTransactions(filter, "", "", trx) {
+		t.Error("expected a non-matching method selector to fail")
+	}
+}
+
+func TestCheckTransactionsCreationsOnly(t *testing.T) {
+	creation := types.NewContractCreation(0, big.NewInt(0), 21000, big.NewInt(1), nil)
+	filter := TrxFilter{CreationsOnly: true}
+	if !checkTransactions(filter, "", "", creation) {
+		t.Error("expected a contract creation to pass CreationsOnly")
+	}
+
+	call := transferTrx(big.NewInt(0), nil)
+	if checkTransactions(filter, "", "", call) {
+		t.Error("expected a call to an existing address to fail CreationsOnly")
+	}
+}
+
+func TestCheckTransactionsValueRange(t *testing.T) {
+	trx := transferTrx(big.NewInt(100), nil)
+
+	filter := TrxFilter{MinValue: big.NewInt(50), MaxValue: big.NewInt(150)}
+	if !checkTransactions(filter, "", "", trx) {
+		t.Error("expected a value within [MinValue, MaxValue] to pass")
+	}
+
+	filter = TrxFilter{MinValue: big.NewInt(101)}
+	if checkTransactions(filter, "", "", trx) {
+		t.Error("expected a value below MinValue to fail")
+	}
+
+	filter = TrxFilter{MaxValue: big.NewInt(99)}
+	if checkTransactions(filter, "", "", trx) {
+		t.Error("expected a value above MaxValue to fail")
+	}
+}
+
+func TestTrxAddressMatches(t *testing.T) {
+	if !trxAddressMatches(nil, nil, "0xsrc", "0xdst") {
+		t.Error("expected no address filter to match everything")
+	}
+	if !trxAddressMatches([]string{"0xsrc"}, nil, "0xsrc", "0xdst") {
+		t.Error("expected a matching Src to pass")
+	}
+	if !trxAddressMatches(nil, []string{"0xdst"}, "0xsrc", "0xdst") {
+		t.Error("expected a matching Dst to pass")
+	}
+	if trxAddressMatches([]string{"0xother"}, []string{"0xother"}, "0xsrc", "0xdst") {
+		t.Error("expected a filter matching neither Src nor Dst to fail")
+	}
+}
+
+func TestCheckNodeKeys(t *testing.T) {
+	key := common.BytesToHash([]byte("account"))
+
+	if !checkNodeKeys(nil, nil, key) {
+		t.Error("expected no key filter to match everything")
+	}
+	if !checkNodeKeys([]common.Hash{key}, nil, key) {
+		t.Error("expected an exact key match to pass")
+	}
+	if checkNodeKeys([]common.Hash{common.BytesToHash([]byte("other"))}, nil, key) {
+		t.Error("expected a non-matching exact key to fail")
+	}
+
+	keyBytes := key.Bytes()
+	prefix := []byte{keyBytes[0] >> 4}
+	if !checkNodeKeys(nil, prefix, key) {
+		t.Error("expected a matching nibble prefix to pass")
+	}
+}
@@ -0,0 +1,255 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	cid "github.com/ipfs/go-cid"
+)
+
+// IPLDPayload is the output structure for the IPLD converter, holding everything
+// the Screener needs in order to filter out and package a ResponsePayload
+type IPLDPayload struct {
+	BlockNumber     *big.Int
+	HeaderRLP       []byte
+	BlockBody       *types.Body
+	TrxMetaData     []TrxMetaData
+	Receipts        types.Receipts
+	ReceiptMetaData []ReceiptMetaData
+	// StateNodes is keyed by account key (keccak256 of the address) and holds
+	// every trie node encountered walking from the state root down to that
+	// key, in root-to-leaf order (root-to-divergence-node order for an
+	// account that does not exist), so that the full path is available for
+	// proof construction without having to re-walk the trie
+	//
+	// This tree has no converter that actually walks a state trie to build
+	// these paths: StateNodes/StorageNodes are only ever populated by
+	// whatever upstream IPLD producer constructs an IPLDPayload, and nothing
+	// in this repo does that yet, so for now they only ever contain entries
+	// for keys touched in the block. filterState/filterStorage build
+	// StateProofs/StorageProofs directly from whatever path is present here
+	// rather than walking a trie themselves; an address/slot that was never
+	// touched (the normal case for a key that doesn't exist) has no entry at
+	// all and so produces no proof, not an exclusion proof, until a producer
+	// that can supply a root-to-divergence path for untouched keys exists.
+	StateNodes map[common.Hash][]StateNode
+	// StorageNodes is keyed by account key and holds, for each of that
+	// account's touched storage slots, every node on the path from the
+	// storage root to the slot, in the same root-to-leaf order as StateNodes
+	StorageNodes map[common.Hash][]StorageNode
+}
+
+// TrxMetaData holds the information about a transaction needed to screen it,
+// without requiring the screener to re-derive it from the transaction itself
+type TrxMetaData struct {
+	Src string
+	Dst string
+}
+
+// ReceiptMetaData holds the information about a receipt needed to screen it.
+// Topics and Addresses are both indexed by log position within the receipt:
+// Topics[i] holds log i's topics in order (Topics[i][0] is its topic0, etc.)
+// and Addresses[i] holds log i's contract address.
+type ReceiptMetaData struct {
+	Topics    [][]string
+	Addresses []string
+}
+
+// StateNode holds a single state trie node, as emitted by the IPLD converter.
+// Path is the hex-nibble path from the state root to this node; Leaf is set
+// on the terminal node of an existing account (an existing account's path
+// ends in a leaf, a non-existent account's path ends at the node where the
+// key's nibbles diverge from every stored key).
+type StateNode struct {
+	Path  []byte
+	Leaf  bool
+	Value []byte
+}
+
+// StorageNode holds a single storage trie node, as emitted by the IPLD
+// converter. Key is the storage slot this node's path leads to; Path and Leaf
+// carry the same root-to-leaf/divergence meaning as StateNode's.
+type StorageNode struct {
+	Key   common.Hash
+	Path  []byte
+	Leaf  bool
+	Value []byte
+}
+
+// StreamFilters is the container for the filters to be used in screening data
+// at a subscription level; these filters are set by the client in their
+// subscription request/config
+type StreamFilters struct {
+	HeaderFilter  HeaderFilter
+	TrxFilter     TrxFilter
+	ReceiptFilter ReceiptFilter
+	StateFilter   StateFilter
+	StorageFilter StorageFilter
+	// Codec selects the output format for the response: CodecRLP (the
+	// default, "") or CodecDAGCBOR ("dag-cbor"). See ResponsePayload.IPLDs.
+	Codec string
+}
+
+// HeaderFilter is used to filter over headers and uncles
+type HeaderFilter struct {
+	Off bool
+	// Starting and ending block number range for this filter
+	// set both to 0 to indicate "all blocks"
+	StartingBlock int64
+	EndingBlock   int64
+	Uncles        bool
+}
+
+// TrxFilter is used to filter over transactions. MethodSigs filters by the
+// 4-byte function selector prefix of the transaction's input data (an OR
+// across the list, e.g. watch "all calls to transfer(address,uint256)"
+// regardless of contract). CreationsOnly restricts to contract-creation
+// transactions (Dst == ""). MinValue/MaxValue bound the transaction's wei
+// value (either may be nil to leave that side unbounded). All of these are
+// ANDed with Src/Dst and with each other.
+//
+// MethodSigs/CreationsOnly/MinValue/MaxValue (and StateFilter/StorageFilter's
+// KeyPrefix) are not yet settable from a toml subscription config: this tree
+// has no cmd/config/subscribe package to thread them through, so only
+// in-process callers can populate them for now.
+type TrxFilter struct {
+	Off           bool
+	StartingBlock int64
+	EndingBlock   int64
+	Src           []string
+	Dst           []string
+	MethodSigs    [][4]byte
+	CreationsOnly bool
+	MinValue      *big.Int
+	MaxValue      *big.Int
+}
+
+// ReceiptFilter is used to filter over receipts. Topics is a positional
+// filter over up to 4 topics per log, matching go-ethereum's
+// FilterQuery.Topics semantics: a nil/empty slice at a position is a
+// wildcard, a non-empty slice is an OR of candidates at that position, and
+// the positions are ANDed together. Addresses filters by the contract
+// address a log was emitted from (an OR across the list), ANDed with Topics.
+// LogsOnly, when set, causes matching receipts to contribute only their
+// individual matching logs (via ResponsePayload.LogsRlp) instead of the
+// entire receipt RLP.
+type ReceiptFilter struct {
+	Off           bool
+	StartingBlock int64
+	EndingBlock   int64
+	Topics        [4][]string
+	Addresses     []string
+	LogsOnly      bool
+}
+
+// StateFilter is used to filter over state nodes. WithProof is experimental:
+// it requests whatever node path IPLDPayload.StateNodes already has recorded
+// for each matching address (see ResponsePayload.StateProofs) in place of
+// just the leaf/intermediate values, but nothing in this tree walks a trie
+// or verifies a hash to build that path, so it is only as complete as
+// whatever upstream producer populated IPLDPayload — today that means a key
+// untouched in the block yields no proof at all rather than a verifiable
+// exclusion proof. Don't rely on it for eth_getProof-equivalent guarantees
+// until a real trie-walking producer backs it. KeyPrefix matches any state
+// key sharing its leading hex nibbles (e.g. to watch all storage under a
+// mapping's computed slot base); it is ORed with Addresses.
+type StateFilter struct {
+	Off               bool
+	StartingBlock     int64
+	EndingBlock       int64
+	Addresses         []string
+	KeyPrefix         []byte
+	IntermediateNodes bool
+	WithProof         bool
+}
+
+// StorageFilter is used to filter over storage nodes. WithProof is the
+// storage-key analog of StateFilter.WithProof and carries the same caveat:
+// experimental, backed only by whatever path IPLDPayload.StorageNodes
+// already has recorded, with no trie walk, no hash verification, and no
+// exclusion proof for a slot untouched in the block. KeyPrefix is the
+// storage-key analog of StateFilter.KeyPrefix: it is ORed with StorageKeys.
+// IntermediateNodes is the storage-key analog of StateFilter.IntermediateNodes:
+// it requests every intermediate trie node on the path to a matching slot,
+// not just the leaf.
+type StorageFilter struct {
+	Off               bool
+	StartingBlock     int64
+	EndingBlock       int64
+	Addresses         []string
+	StorageKeys       []string
+	KeyPrefix         []byte
+	IntermediateNodes bool
+	WithProof         bool
+}
+
+// ResponsePayload holds the data that satisfies the StreamFilters for a given
+// IPLDPayload, ready to be relayed to subscribers
+type ResponsePayload struct {
+	HeadersRlp      [][]byte
+	UnclesRlp       [][]byte
+	TransactionsRlp [][]byte
+	ReceiptsRlp     [][]byte
+	// StateNodesRlp holds, per matching account key, every matching node's
+	// RLP (just the leaf when IntermediateNodes is unset, leaf+intermediates
+	// when it is set) — never just the last one seen
+	StateNodesRlp map[common.Hash][][]byte
+	// StorageNodesRlp mirrors StateNodesRlp for storage slots, keyed first by
+	// account key and then by storage key, holding every matching node's RLP
+	// for that slot (just the leaf when IntermediateNodes is unset,
+	// leaf+intermediates when it is set) — never just the last one seen
+	StorageNodesRlp map[common.Hash]map[common.Hash][][]byte
+	// LogsRlp holds individual matching logs, RLP-encoded and keyed by the
+	// hash of the transaction that emitted them, populated in place of
+	// ReceiptsRlp when a ReceiptFilter has LogsOnly set
+	LogsRlp map[common.Hash][][]byte
+	// StateProofs holds, per requested account key, the ordered (root-to-leaf
+	// or root-to-divergence) list of RLP-encoded trie nodes proving that
+	// account's inclusion or exclusion against the block's stateRoot. A
+	// client verifies it by re-hashing each node and following the nibble
+	// path of keccak256(address), checking that each branch/extension node's
+	// child reference matches the keccak256 of the next node in the list
+	// (nodes under 32 bytes are embedded rather than hash-referenced and are
+	// taken as-is). Populated in place of StateNodesRlp when StateFilter has
+	// WithProof set. See IPLDPayload.StateNodes: an untouched key has no
+	// path to build a proof from, so WithProof silently omits it rather than
+	// returning an exclusion proof.
+	StateProofs map[common.Hash][][]byte
+	// StorageProofs mirrors StateProofs for storage slots, keyed first by
+	// account key and then by storage key, verified the same way but against
+	// that account's storageRoot and keccak256(slot).
+	StorageProofs map[common.Hash]map[common.Hash][][]byte
+	// IPLDs holds this response's data as IPLD blocks keyed by their CID,
+	// populated instead of the *Rlp fields when StreamFilters.Codec is
+	// CodecDAGCBOR
+	IPLDs map[cid.Cid][]byte
+}
+
+// AddressToKey hashes an address to derive the state trie key it is stored at
+func AddressToKey(addr common.Address) common.Hash {
+	return common.BytesToHash(crypto.Keccak256(addr.Bytes()))
+}
+
+// HexToKey hashes the bytes behind a hex string to derive the storage trie key
+// it is stored at; input may be 0x-prefixed or not
+func HexToKey(hex string) common.Hash {
+	return common.BytesToHash(crypto.Keccak256(common.FromHex(hex)))
+}